@@ -0,0 +1,29 @@
+package admission
+
+import (
+	"k8s.io/kubernetes/pkg/client/record"
+
+	projectcache "github.com/openshift/origin/pkg/project/cache"
+)
+
+// WantsProjectCache should be implemented by admission plugins that need
+// access to a project cache. The apiserver plugin initializer calls
+// SetProjectCache before Validate.
+type WantsProjectCache interface {
+	SetProjectCache(*projectcache.ProjectCache)
+	Validator
+}
+
+// WantsEventRecorder should be implemented by admission plugins that need to
+// record events on the objects they admit. The apiserver plugin initializer
+// calls SetEventRecorder before Validate.
+type WantsEventRecorder interface {
+	SetEventRecorder(record.EventRecorder)
+	Validator
+}
+
+// Validator is implemented by admission plugins whose configuration and
+// wiring should be checked once all of the Wants* setters above have run.
+type Validator interface {
+	Validate() error
+}
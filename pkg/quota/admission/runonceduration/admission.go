@@ -4,20 +4,40 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
 
 	"k8s.io/kubernetes/pkg/admission"
 	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/batch"
 	clientset "k8s.io/kubernetes/pkg/client/clientset_generated/internalclientset"
+	"k8s.io/kubernetes/pkg/client/record"
+	"k8s.io/kubernetes/pkg/labels"
+	"k8s.io/kubernetes/pkg/runtime"
 
 	oadmission "github.com/openshift/origin/pkg/cmd/server/admission"
 	configlatest "github.com/openshift/origin/pkg/cmd/server/api/latest"
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
 	projectcache "github.com/openshift/origin/pkg/project/cache"
 	"github.com/openshift/origin/pkg/quota/admission/runonceduration/api"
 	"github.com/openshift/origin/pkg/quota/admission/runonceduration/api/validation"
 )
 
+// Sources identify, for events and metrics, which piece of configuration
+// caused the plugin to set a pod's ActiveDeadlineSeconds.
+const (
+	sourceProjectAnnotation = "project-annotation"
+	sourceGlobalConfig      = "global-config"
+	sourceRulePrefix        = "rule:"
+)
+
 func init() {
+	registerMetrics()
 	admission.RegisterPlugin("RunOnceDuration", func(client clientset.Interface, config io.Reader) (admission.Interface, error) {
 		pluginConfig, err := readConfig(config)
 		if err != nil {
@@ -56,27 +76,92 @@ func NewRunOnceDuration(config *api.RunOnceDurationConfig) admission.Interface {
 
 type runOnceDuration struct {
 	*admission.Handler
-	config *api.RunOnceDurationConfig
-	cache  *projectcache.ProjectCache
+	config   *api.RunOnceDurationConfig
+	cache    *projectcache.ProjectCache
+	recorder record.EventRecorder
 }
 
 var _ = oadmission.WantsProjectCache(&runOnceDuration{})
+var _ = oadmission.WantsEventRecorder(&runOnceDuration{})
 var _ = oadmission.Validator(&runOnceDuration{})
 
 func (a *runOnceDuration) Admit(attributes admission.Attributes) error {
-	switch {
-	case a.config == nil,
-		attributes.GetResource() != kapi.Resource("pods"),
-		len(attributes.GetSubresource()) > 0:
+	if a.config == nil || len(attributes.GetSubresource()) > 0 {
+		return nil
+	}
+
+	switch attributes.GetResource() {
+	case kapi.Resource("pods"):
+		return a.admitPod(attributes)
+	case batch.Resource("jobs"):
+		return a.admitJob(attributes)
+	case batch.Resource("cronjobs"):
+		return a.admitCronJob(attributes)
+	case deployapi.Resource("deploymentconfigs"):
+		return a.admitDeploymentConfig(attributes)
+	case kapi.Resource("replicationcontrollers"):
+		return a.admitReplicationController(attributes)
+	default:
 		return nil
 	}
+}
+
+func (a *runOnceDuration) admitPod(attributes admission.Attributes) error {
 	pod, ok := attributes.GetObject().(*kapi.Pod)
 	if !ok {
 		return admission.NewForbidden(attributes, fmt.Errorf("unexpected object: %#v", attributes.GetObject()))
 	}
+	return a.admitPodSpec(attributes, pod, &pod.ObjectMeta, &pod.Spec)
+}
 
+func (a *runOnceDuration) admitJob(attributes admission.Attributes) error {
+	job, ok := attributes.GetObject().(*batch.Job)
+	if !ok {
+		return admission.NewForbidden(attributes, fmt.Errorf("unexpected object: %#v", attributes.GetObject()))
+	}
+	return a.admitPodSpec(attributes, job, &job.Spec.Template.ObjectMeta, &job.Spec.Template.Spec)
+}
+
+func (a *runOnceDuration) admitCronJob(attributes admission.Attributes) error {
+	cronJob, ok := attributes.GetObject().(*batch.CronJob)
+	if !ok {
+		return admission.NewForbidden(attributes, fmt.Errorf("unexpected object: %#v", attributes.GetObject()))
+	}
+	template := &cronJob.Spec.JobTemplate.Spec.Template
+	return a.admitPodSpec(attributes, cronJob, &template.ObjectMeta, &template.Spec)
+}
+
+func (a *runOnceDuration) admitDeploymentConfig(attributes admission.Attributes) error {
+	dc, ok := attributes.GetObject().(*deployapi.DeploymentConfig)
+	if !ok {
+		return admission.NewForbidden(attributes, fmt.Errorf("unexpected object: %#v", attributes.GetObject()))
+	}
+	if dc.Spec.Template == nil {
+		return nil
+	}
+	return a.admitPodSpec(attributes, dc, &dc.Spec.Template.ObjectMeta, &dc.Spec.Template.Spec)
+}
+
+func (a *runOnceDuration) admitReplicationController(attributes admission.Attributes) error {
+	rc, ok := attributes.GetObject().(*kapi.ReplicationController)
+	if !ok {
+		return admission.NewForbidden(attributes, fmt.Errorf("unexpected object: %#v", attributes.GetObject()))
+	}
+	if rc.Spec.Template == nil {
+		return nil
+	}
+	return a.admitPodSpec(attributes, rc, &rc.Spec.Template.ObjectMeta, &rc.Spec.Template.Spec)
+}
+
+// admitPodSpec applies the RunOnceDuration policy to a run-once pod spec,
+// regardless of whether it belongs to a bare Pod or to the pod template of a
+// Job, CronJob, DeploymentConfig, or ReplicationController. eventObject is
+// the object the admission Event, if any, is recorded against; meta is the
+// ObjectMeta that wraps spec, used for selector matching and, in Warn/Audit
+// mode, for recording the would-set annotation.
+func (a *runOnceDuration) admitPodSpec(attributes admission.Attributes, eventObject runtime.Object, meta *kapi.ObjectMeta, spec *kapi.PodSpec) error {
 	// Only update pods with a restart policy of Never or OnFailure
-	switch pod.Spec.RestartPolicy {
+	switch spec.RestartPolicy {
 	case kapi.RestartPolicyNever,
 		kapi.RestartPolicyOnFailure:
 		// continue
@@ -84,17 +169,225 @@ func (a *runOnceDuration) Admit(attributes admission.Attributes) error {
 		return nil
 	}
 
-	appliedProjectOverride, err := a.applyProjectAnnotationOverride(attributes.GetNamespace(), pod)
+	ns, err := a.cache.GetNamespace(attributes.GetNamespace())
 	if err != nil {
+		return admission.NewForbidden(attributes, fmt.Errorf("error looking up namespace: %v", err))
+	}
+
+	if overrideValue, hasOverride, err := projectAnnotationOverride(ns); err != nil {
 		return admission.NewForbidden(attributes, err)
+	} else if hasOverride {
+		if applied, value := a.computeDeadline(spec.ActiveDeadlineSeconds, overrideValue); applied {
+			a.decide(attributes, eventObject, meta, spec, sourceProjectAnnotation, ns.Name, value)
+		}
+		return nil
 	}
 
-	if !appliedProjectOverride && a.config.ActiveDeadlineSecondsOverride != nil {
-		pod.Spec.ActiveDeadlineSeconds = a.config.ActiveDeadlineSecondsOverride
+	if bound, source, ok := a.resolveDeadline(ns, meta.Labels, spec); ok {
+		if applied, value := a.computeDeadline(spec.ActiveDeadlineSeconds, bound); applied {
+			a.decide(attributes, eventObject, meta, spec, source, ns.Name, value)
+		}
 	}
 	return nil
 }
 
+// decide carries out a resolved ActiveDeadlineSeconds decision according to
+// the plugin's EnforcementMode. In every mode it records a metric, labelled
+// with whether the decision was enforced. In Enforce mode (the default), it
+// mutates spec and records an Event. In Warn and Audit mode, it leaves spec
+// untouched, records the decision as an annotation and a structured log line
+// instead, and in Warn mode additionally logs a non-fatal admission warning.
+func (a *runOnceDuration) decide(attributes admission.Attributes, eventObject runtime.Object, meta *kapi.ObjectMeta, spec *kapi.PodSpec, source, namespace string, value int64) {
+	enforced := a.config.EnforcementMode != api.RunOnceDurationEnforcementModeWarn && a.config.EnforcementMode != api.RunOnceDurationEnforcementModeAudit
+	observeAdmission(source, namespace, value, enforced)
+
+	switch a.config.EnforcementMode {
+	case api.RunOnceDurationEnforcementModeWarn, api.RunOnceDurationEnforcementModeAudit:
+		setWouldSetAnnotation(meta, value)
+		glog.V(2).Infof("runonceduration: namespace=%s name=%s source=%s activeDeadlineSeconds=%d enforcementMode=%s",
+			namespace, meta.Name, source, value, a.config.EnforcementMode)
+		if a.config.EnforcementMode == api.RunOnceDurationEnforcementModeWarn {
+			glog.Warningf("runonceduration: %s %q in namespace %q would have ActiveDeadlineSeconds set to %d, source=%s",
+				attributes.GetResource().Resource, meta.Name, namespace, value, source)
+		}
+	default:
+		spec.ActiveDeadlineSeconds = &value
+		if a.recorder != nil {
+			a.recorder.Eventf(eventObject, kapi.EventTypeNormal, "ActiveDeadlineSecondsSet",
+				"ActiveDeadlineSeconds set to %d by RunOnceDuration admission, source=%s", value, source)
+		}
+	}
+}
+
+// setWouldSetAnnotation records, on meta, the ActiveDeadlineSeconds value
+// that Enforce mode would have applied.
+func setWouldSetAnnotation(meta *kapi.ObjectMeta, value int64) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[api.WouldSetActiveDeadlineSecondsAnnotation] = strconv.FormatInt(value, 10)
+}
+
+// computeDeadline determines whether, and to what value, ActiveDeadlineSeconds
+// should be set given the plugin's configured Mode, the pod's existing value
+// (if any), and the resolved bound from a Rule or the global override. It
+// does not mutate anything. In Override mode (the default), the bound always
+// applies. In Min/Max mode, the bound only raises/lowers an existing value,
+// and applies outright if there is none. In Clamp mode, an existing value is
+// constrained to [MinActiveDeadlineSeconds, bound].
+func (a *runOnceDuration) computeDeadline(existing *int64, bound int64) (applied bool, value int64) {
+	switch a.config.Mode {
+	case api.RunOnceDurationModeMin:
+		if existing == nil || *existing < bound {
+			return true, bound
+		}
+	case api.RunOnceDurationModeMax:
+		if existing == nil || *existing > bound {
+			return true, bound
+		}
+	case api.RunOnceDurationModeClamp:
+		switch {
+		case existing == nil:
+			return true, bound
+		case a.config.MinActiveDeadlineSeconds != nil && *existing < *a.config.MinActiveDeadlineSeconds:
+			return true, *a.config.MinActiveDeadlineSeconds
+		case *existing > bound:
+			return true, bound
+		}
+	default:
+		return true, bound
+	}
+	return false, 0
+}
+
+// resolveDeadline returns the ActiveDeadlineSeconds bound that applies to a
+// pod spec given the plugin's configured rules and global override, the
+// source that produced it, and whether any of them matched. Rules are
+// evaluated in order; the first rule whose selectors match wins. If no rule
+// matches, the global ActiveDeadlineSecondsOverride is used, if configured.
+func (a *runOnceDuration) resolveDeadline(ns *kapi.Namespace, podLabels map[string]string, spec *kapi.PodSpec) (bound int64, source string, ok bool) {
+	for _, rule := range a.config.Rules {
+		if ruleMatches(rule, ns, podLabels, spec) {
+			return rule.ActiveDeadlineSeconds, sourceRulePrefix + rule.Name, true
+		}
+	}
+	if a.config.ActiveDeadlineSecondsOverride != nil {
+		return *a.config.ActiveDeadlineSecondsOverride, sourceGlobalConfig, true
+	}
+	return 0, "", false
+}
+
+// ruleMatches returns true if the rule's namespace selector, pod selector,
+// and container name pattern (when set) all match the given namespace/pod
+// spec.
+func ruleMatches(rule api.RunOnceDurationRule, ns *kapi.Namespace, podLabels map[string]string, spec *kapi.PodSpec) bool {
+	if rule.NamespaceSelector != nil {
+		selector, err := unversioned.LabelSelectorAsSelector(rule.NamespaceSelector)
+		if err != nil || !selector.Matches(labelSet(ns.Labels)) {
+			return false
+		}
+	}
+	if rule.Selector != nil {
+		selector, err := unversioned.LabelSelectorAsSelector(rule.Selector)
+		if err != nil || !selector.Matches(labelSet(podLabels)) {
+			return false
+		}
+	}
+	if len(rule.ContainerNamePattern) > 0 && !anyContainerMatches(rule.ContainerNamePattern, spec) {
+		return false
+	}
+	return true
+}
+
+// anyContainerMatches returns true if any container in spec has a name or
+// image matching the given glob pattern. Unlike path.Match, the glob's '*'
+// and '?' are not bound by '/', since container images routinely contain
+// registry/repository paths (e.g. "registry.example.com/myorg/builder:v1")
+// that a pattern like "*builder*" must still be able to match.
+func anyContainerMatches(pattern string, spec *kapi.PodSpec) bool {
+	matcher, err := globMatcher(pattern)
+	if err != nil {
+		return false
+	}
+	for _, c := range spec.Containers {
+		if matcher.MatchString(c.Name) || matcher.MatchString(c.Image) {
+			return true
+		}
+	}
+	return false
+}
+
+// globMatcherCache caches the regexps built by globMatcher, since the same
+// rule's ContainerNamePattern is evaluated on every admission request.
+var globMatcherCache sync.Map // pattern string -> *regexp.Regexp
+
+// globMatcher compiles a shell glob pattern into a regexp anchored to the
+// whole string. It supports the same syntax as path.Match -- '*' matches any
+// sequence of characters, '?' matches any single character, and '[...]'
+// (optionally negated with a leading '^') matches a character class -- except
+// that '\\' is an ordinary character rather than an escape outside a
+// character class, and '*' and '?' are not bound by '/', since container images routinely
+// contain registry/repository paths that a pattern like "*builder*" must
+// still be able to match.
+func globMatcher(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := globMatcherCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(globToRegexpString(pattern))
+	if err != nil {
+		return nil, err
+	}
+	globMatcherCache.Store(pattern, re)
+	return re, nil
+}
+
+func globToRegexpString(pattern string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			if end := globClassEnd(runes, i); end >= 0 {
+				b.WriteString("[")
+				b.WriteString(string(runes[i+1 : end]))
+				b.WriteString("]")
+				i = end
+				continue
+			}
+			b.WriteString(regexp.QuoteMeta("["))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// globClassEnd returns the index of the ']' that closes the character class
+// starting at runes[start] (which must be '['), or -1 if there is none, in
+// which case '[' is treated as a literal character.
+func globClassEnd(runes []rune, start int) int {
+	i := start + 1
+	if i < len(runes) && runes[i] == '^' {
+		i++
+	}
+	for ; i < len(runes); i++ {
+		if runes[i] == ']' {
+			return i
+		}
+	}
+	return -1
+}
+
+func (a *runOnceDuration) SetEventRecorder(recorder record.EventRecorder) {
+	a.recorder = recorder
+}
+
 func (a *runOnceDuration) SetProjectCache(cache *projectcache.ProjectCache) {
 	a.cache = cache
 }
@@ -106,22 +399,27 @@ func (a *runOnceDuration) Validate() error {
 	return nil
 }
 
-func (a *runOnceDuration) applyProjectAnnotationOverride(namespace string, pod *kapi.Pod) (bool, error) {
-	ns, err := a.cache.GetNamespace(namespace)
-	if err != nil {
-		return false, fmt.Errorf("error looking up pod namespace: %v", err)
-	}
+// projectAnnotationOverride returns the ActiveDeadlineSeconds override
+// configured on ns via ActiveDeadlineSecondsOverrideAnnotation, if any.
+func projectAnnotationOverride(ns *kapi.Namespace) (int64, bool, error) {
 	if ns.Annotations == nil {
-		return false, nil
+		return 0, false, nil
 	}
 	override, hasOverride := ns.Annotations[api.ActiveDeadlineSecondsOverrideAnnotation]
 	if !hasOverride {
-		return false, nil
+		return 0, false, nil
 	}
 	overrideInt64, err := strconv.ParseInt(override, 10, 64)
 	if err != nil {
-		return false, fmt.Errorf("cannot parse the ActiveDeadlineSeconds override (%s) for project %s: %v", override, ns.Name, err)
+		return 0, false, fmt.Errorf("cannot parse the ActiveDeadlineSeconds override (%s) for project %s: %v", override, ns.Name, err)
+	}
+	return overrideInt64, true, nil
+}
+
+// labelSet converts a plain label map to a labels.Set for selector matching.
+func labelSet(l map[string]string) labels.Set {
+	if l == nil {
+		return labels.Set{}
 	}
-	pod.Spec.ActiveDeadlineSeconds = &overrideInt64
-	return true, nil
+	return labels.Set(l)
 }
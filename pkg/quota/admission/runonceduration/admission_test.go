@@ -0,0 +1,418 @@
+package runonceduration
+
+import (
+	"testing"
+
+	"k8s.io/kubernetes/pkg/admission"
+	kapi "k8s.io/kubernetes/pkg/api"
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/apis/batch"
+	"k8s.io/kubernetes/pkg/runtime"
+
+	deployapi "github.com/openshift/origin/pkg/deploy/api"
+	"github.com/openshift/origin/pkg/quota/admission/runonceduration/api"
+)
+
+func int64p(v int64) *int64 { return &v }
+
+func TestComputeDeadline(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        api.RunOnceDurationMode
+		min         *int64
+		existing    *int64
+		bound       int64
+		wantApplied bool
+		wantValue   int64
+	}{
+		{
+			name:        "override always applies, even lowering an existing value",
+			mode:        api.RunOnceDurationModeOverride,
+			existing:    int64p(1800),
+			bound:       600,
+			wantApplied: true,
+			wantValue:   600,
+		},
+		{
+			name:        "min leaves a higher existing value alone",
+			mode:        api.RunOnceDurationModeMin,
+			existing:    int64p(1800),
+			bound:       600,
+			wantApplied: false,
+		},
+		{
+			name:        "min raises a lower existing value",
+			mode:        api.RunOnceDurationModeMin,
+			existing:    int64p(300),
+			bound:       600,
+			wantApplied: true,
+			wantValue:   600,
+		},
+		{
+			name:        "min applies when there is no existing value",
+			mode:        api.RunOnceDurationModeMin,
+			existing:    nil,
+			bound:       600,
+			wantApplied: true,
+			wantValue:   600,
+		},
+		{
+			name:        "max leaves a lower existing value alone",
+			mode:        api.RunOnceDurationModeMax,
+			existing:    int64p(300),
+			bound:       600,
+			wantApplied: false,
+		},
+		{
+			name:        "max lowers a higher existing value",
+			mode:        api.RunOnceDurationModeMax,
+			existing:    int64p(1800),
+			bound:       600,
+			wantApplied: true,
+			wantValue:   600,
+		},
+		{
+			name:        "clamp applies the bound when there is no existing value",
+			mode:        api.RunOnceDurationModeClamp,
+			min:         int64p(300),
+			existing:    nil,
+			bound:       1800,
+			wantApplied: true,
+			wantValue:   1800,
+		},
+		{
+			name:        "clamp raises an existing value below the floor",
+			mode:        api.RunOnceDurationModeClamp,
+			min:         int64p(300),
+			existing:    int64p(60),
+			bound:       1800,
+			wantApplied: true,
+			wantValue:   300,
+		},
+		{
+			name:        "clamp lowers an existing value above the bound",
+			mode:        api.RunOnceDurationModeClamp,
+			min:         int64p(300),
+			existing:    int64p(3600),
+			bound:       1800,
+			wantApplied: true,
+			wantValue:   1800,
+		},
+		{
+			name:        "clamp leaves an existing value already within range alone",
+			mode:        api.RunOnceDurationModeClamp,
+			min:         int64p(300),
+			existing:    int64p(600),
+			bound:       1800,
+			wantApplied: false,
+		},
+		{
+			name:        "clamp with no configured floor still applies the bound as a ceiling",
+			mode:        api.RunOnceDurationModeClamp,
+			min:         nil,
+			existing:    int64p(3600),
+			bound:       1800,
+			wantApplied: true,
+			wantValue:   1800,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &runOnceDuration{config: &api.RunOnceDurationConfig{Mode: tc.mode, MinActiveDeadlineSeconds: tc.min}}
+			applied, value := a.computeDeadline(tc.existing, tc.bound)
+			if applied != tc.wantApplied {
+				t.Fatalf("applied = %v, want %v", applied, tc.wantApplied)
+			}
+			if applied && value != tc.wantValue {
+				t.Fatalf("value = %d, want %d", value, tc.wantValue)
+			}
+		})
+	}
+}
+
+func TestResolveDeadline(t *testing.T) {
+	ns := &kapi.Namespace{
+		ObjectMeta: kapi.ObjectMeta{Name: "ci", Labels: map[string]string{"env": "ci"}},
+	}
+	podLabels := map[string]string{"app": "builder"}
+	spec := &kapi.PodSpec{Containers: []kapi.Container{{Name: "builder", Image: "registry/build:v1"}}}
+
+	tests := []struct {
+		name       string
+		config     api.RunOnceDurationConfig
+		wantBound  int64
+		wantSource string
+		wantOK     bool
+	}{
+		{
+			name:   "no rules and no global override",
+			config: api.RunOnceDurationConfig{},
+			wantOK: false,
+		},
+		{
+			name: "falls back to the global override when no rule matches",
+			config: api.RunOnceDurationConfig{
+				Rules: []api.RunOnceDurationRule{
+					{Name: "other", Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": "nomatch"}}, ActiveDeadlineSeconds: 60},
+				},
+				ActiveDeadlineSecondsOverride: int64p(900),
+			},
+			wantBound:  900,
+			wantSource: sourceGlobalConfig,
+			wantOK:     true,
+		},
+		{
+			name: "first matching rule wins over a later one that would also match",
+			config: api.RunOnceDurationConfig{
+				Rules: []api.RunOnceDurationRule{
+					{Name: "by-namespace", NamespaceSelector: &unversioned.LabelSelector{MatchLabels: map[string]string{"env": "ci"}}, ActiveDeadlineSeconds: 600},
+					{Name: "by-pod-label", Selector: &unversioned.LabelSelector{MatchLabels: map[string]string{"app": "builder"}}, ActiveDeadlineSeconds: 1200},
+				},
+			},
+			wantBound:  600,
+			wantSource: sourceRulePrefix + "by-namespace",
+			wantOK:     true,
+		},
+		{
+			name: "a rule that doesn't match is skipped in favor of one that does",
+			config: api.RunOnceDurationConfig{
+				Rules: []api.RunOnceDurationRule{
+					{Name: "wrong-namespace", NamespaceSelector: &unversioned.LabelSelector{MatchLabels: map[string]string{"env": "prod"}}, ActiveDeadlineSeconds: 600},
+					{Name: "right-container", ContainerNamePattern: "build*", ActiveDeadlineSeconds: 1200},
+				},
+			},
+			wantBound:  1200,
+			wantSource: sourceRulePrefix + "right-container",
+			wantOK:     true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &runOnceDuration{config: &tc.config}
+			bound, source, ok := a.resolveDeadline(ns, podLabels, spec)
+			if ok != tc.wantOK {
+				t.Fatalf("ok = %v, want %v", ok, tc.wantOK)
+			}
+			if !tc.wantOK {
+				return
+			}
+			if bound != tc.wantBound {
+				t.Errorf("bound = %d, want %d", bound, tc.wantBound)
+			}
+			if source != tc.wantSource {
+				t.Errorf("source = %q, want %q", source, tc.wantSource)
+			}
+		})
+	}
+}
+
+func TestAnyContainerMatches(t *testing.T) {
+	spec := &kapi.PodSpec{Containers: []kapi.Container{
+		{Name: "build", Image: "registry.example.com/myorg/builder:v1"},
+		{Name: "app", Image: "registry.example.com/myorg/app:v1"},
+	}}
+
+	if !anyContainerMatches("build*", spec) {
+		t.Error("expected pattern matching container name to match")
+	}
+	if !anyContainerMatches("*builder*", spec) {
+		t.Error("expected pattern matching a container image path across '/' to match")
+	}
+	if anyContainerMatches("nomatch*", spec) {
+		t.Error("expected non-matching pattern not to match")
+	}
+
+	classSpec := &kapi.PodSpec{Containers: []kapi.Container{{Name: "worker-1"}}}
+	if !anyContainerMatches("worker-[12]", classSpec) {
+		t.Error("expected a character class pattern to match, as with path.Match")
+	}
+	if anyContainerMatches("worker-[^12]", classSpec) {
+		t.Error("expected a negated character class pattern not to match")
+	}
+}
+
+func TestProjectAnnotationOverride(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		wantValue   int64
+		wantHas     bool
+		wantErr     bool
+	}{
+		{name: "no annotations", wantHas: false},
+		{name: "annotation absent", annotations: map[string]string{"other": "1"}, wantHas: false},
+		{name: "valid override", annotations: map[string]string{api.ActiveDeadlineSecondsOverrideAnnotation: "1800"}, wantValue: 1800, wantHas: true},
+		{name: "invalid override", annotations: map[string]string{api.ActiveDeadlineSecondsOverrideAnnotation: "not-a-number"}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ns := &kapi.Namespace{ObjectMeta: kapi.ObjectMeta{Name: "test", Annotations: tc.annotations}}
+			value, has, err := projectAnnotationOverride(ns)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("expected an error, got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if has != tc.wantHas {
+				t.Fatalf("has = %v, want %v", has, tc.wantHas)
+			}
+			if has && value != tc.wantValue {
+				t.Fatalf("value = %d, want %d", value, tc.wantValue)
+			}
+		})
+	}
+}
+
+// TestDecideResourceMutation verifies that decide(), given the ObjectMeta and
+// PodSpec pointers each admit* wrapper extracts from its resource type,
+// mutates the ActiveDeadlineSeconds field embedded in that resource's pod
+// template, for every pod-template resource RunOnceDuration supports (Job,
+// CronJob, DeploymentConfig, and ReplicationController).
+func TestDecideResourceMutation(t *testing.T) {
+	attributes := admission.NewAttributesRecord(nil, nil, unversioned.GroupVersionKind{}, "ci", "example",
+		kapi.Resource("pods").WithVersion(""), "", admission.Create, nil)
+
+	newSpec := func() kapi.PodSpec {
+		return kapi.PodSpec{RestartPolicy: kapi.RestartPolicyNever}
+	}
+
+	job := &batch.Job{Spec: batch.JobSpec{Template: kapi.PodTemplateSpec{Spec: newSpec()}}}
+	a := &runOnceDuration{config: &api.RunOnceDurationConfig{}}
+	a.decide(attributes, job, &job.Spec.Template.ObjectMeta, &job.Spec.Template.Spec, sourceGlobalConfig, "ci", 600)
+	if job.Spec.Template.Spec.ActiveDeadlineSeconds == nil || *job.Spec.Template.Spec.ActiveDeadlineSeconds != 600 {
+		t.Fatalf("job template ActiveDeadlineSeconds = %v, want 600", job.Spec.Template.Spec.ActiveDeadlineSeconds)
+	}
+
+	cronJob := &batch.CronJob{Spec: batch.CronJobSpec{JobTemplate: batch.JobTemplateSpec{Spec: batch.JobSpec{Template: kapi.PodTemplateSpec{Spec: newSpec()}}}}}
+	template := &cronJob.Spec.JobTemplate.Spec.Template
+	a.decide(attributes, cronJob, &template.ObjectMeta, &template.Spec, sourceGlobalConfig, "ci", 600)
+	if template.Spec.ActiveDeadlineSeconds == nil || *template.Spec.ActiveDeadlineSeconds != 600 {
+		t.Fatalf("cron job template ActiveDeadlineSeconds = %v, want 600", template.Spec.ActiveDeadlineSeconds)
+	}
+
+	dc := &deployapi.DeploymentConfig{Spec: deployapi.DeploymentConfigSpec{Template: &kapi.PodTemplateSpec{Spec: newSpec()}}}
+	a.decide(attributes, dc, &dc.Spec.Template.ObjectMeta, &dc.Spec.Template.Spec, sourceGlobalConfig, "ci", 600)
+	if dc.Spec.Template.Spec.ActiveDeadlineSeconds == nil || *dc.Spec.Template.Spec.ActiveDeadlineSeconds != 600 {
+		t.Fatalf("deployment config template ActiveDeadlineSeconds = %v, want 600", dc.Spec.Template.Spec.ActiveDeadlineSeconds)
+	}
+
+	rc := &kapi.ReplicationController{Spec: kapi.ReplicationControllerSpec{Template: &kapi.PodTemplateSpec{Spec: newSpec()}}}
+	a.decide(attributes, rc, &rc.Spec.Template.ObjectMeta, &rc.Spec.Template.Spec, sourceGlobalConfig, "ci", 600)
+	if rc.Spec.Template.Spec.ActiveDeadlineSeconds == nil || *rc.Spec.Template.Spec.ActiveDeadlineSeconds != 600 {
+		t.Fatalf("replication controller template ActiveDeadlineSeconds = %v, want 600", rc.Spec.Template.Spec.ActiveDeadlineSeconds)
+	}
+
+	// Warn/Audit mode must not mutate the spec; it only annotates the template.
+	warnJob := &batch.Job{Spec: batch.JobSpec{Template: kapi.PodTemplateSpec{Spec: newSpec()}}}
+	warnConfig := &runOnceDuration{config: &api.RunOnceDurationConfig{EnforcementMode: api.RunOnceDurationEnforcementModeWarn}}
+	warnConfig.decide(attributes, warnJob, &warnJob.Spec.Template.ObjectMeta, &warnJob.Spec.Template.Spec, sourceGlobalConfig, "ci", 600)
+	if warnJob.Spec.Template.Spec.ActiveDeadlineSeconds != nil {
+		t.Fatalf("Warn mode mutated ActiveDeadlineSeconds to %v, want untouched", warnJob.Spec.Template.Spec.ActiveDeadlineSeconds)
+	}
+	if got := warnJob.Spec.Template.Annotations[api.WouldSetActiveDeadlineSecondsAnnotation]; got != "600" {
+		t.Fatalf("would-set annotation = %q, want %q", got, "600")
+	}
+}
+
+// TestAdmitDispatch exercises Admit's resource-routing switch and the type
+// assertions and nil-template guards in each admitX wrapper, without going
+// through a ProjectCache: every case here is expected to return before
+// admitPodSpec would call a.cache.GetNamespace.
+func TestAdmitDispatch(t *testing.T) {
+	tests := []struct {
+		name        string
+		resource    unversioned.GroupVersionResource
+		subresource string
+		object      runtime.Object
+		nilConfig   bool
+		wantErr     bool
+	}{
+		{
+			name:     "wrong object type for pods is forbidden",
+			resource: kapi.Resource("pods").WithVersion(""),
+			object:   &kapi.Service{},
+			wantErr:  true,
+		},
+		{
+			name:     "wrong object type for jobs is forbidden",
+			resource: batch.Resource("jobs").WithVersion(""),
+			object:   &kapi.Service{},
+			wantErr:  true,
+		},
+		{
+			name:     "wrong object type for cronjobs is forbidden",
+			resource: batch.Resource("cronjobs").WithVersion(""),
+			object:   &kapi.Service{},
+			wantErr:  true,
+		},
+		{
+			name:     "wrong object type for deploymentconfigs is forbidden",
+			resource: deployapi.Resource("deploymentconfigs").WithVersion(""),
+			object:   &kapi.Service{},
+			wantErr:  true,
+		},
+		{
+			name:     "wrong object type for replicationcontrollers is forbidden",
+			resource: kapi.Resource("replicationcontrollers").WithVersion(""),
+			object:   &kapi.Service{},
+			wantErr:  true,
+		},
+		{
+			name:     "deployment config with no pod template is a no-op",
+			resource: deployapi.Resource("deploymentconfigs").WithVersion(""),
+			object:   &deployapi.DeploymentConfig{},
+			wantErr:  false,
+		},
+		{
+			name:     "replication controller with no pod template is a no-op",
+			resource: kapi.Resource("replicationcontrollers").WithVersion(""),
+			object:   &kapi.ReplicationController{},
+			wantErr:  false,
+		},
+		{
+			name:     "unrecognized resource is a no-op",
+			resource: kapi.Resource("secrets").WithVersion(""),
+			object:   &kapi.Secret{},
+			wantErr:  false,
+		},
+		{
+			name:        "subresource updates are skipped before any type assertion",
+			resource:    kapi.Resource("pods").WithVersion(""),
+			subresource: "status",
+			object:      &kapi.Service{},
+			wantErr:     false,
+		},
+		{
+			name:      "a plugin with no config is a no-op",
+			resource:  kapi.Resource("pods").WithVersion(""),
+			object:    &kapi.Service{},
+			nilConfig: true,
+			wantErr:   false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			a := &runOnceDuration{config: &api.RunOnceDurationConfig{}}
+			if tc.nilConfig {
+				a.config = nil
+			}
+			attributes := admission.NewAttributesRecord(tc.object, nil, unversioned.GroupVersionKind{}, "ci", "example",
+				tc.resource, tc.subresource, admission.Create, nil)
+			err := a.Admit(attributes)
+			if tc.wantErr && err == nil {
+				t.Fatal("expected an error, got none")
+			}
+			if !tc.wantErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
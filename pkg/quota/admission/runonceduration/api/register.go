@@ -0,0 +1,21 @@
+package api
+
+import (
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// SchemeGroupVersion is the internal group version used to register the
+// runonceduration admission config types.
+var SchemeGroupVersion = unversioned.GroupVersion{Group: "", Version: runtime.APIVersionInternal}
+
+// SchemeBuilder collects functions that add internal types to the
+// runonceduration admission config API group.
+var SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&RunOnceDurationConfig{},
+	)
+	return nil
+}
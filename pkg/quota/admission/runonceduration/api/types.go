@@ -0,0 +1,117 @@
+package api
+
+import (
+	"k8s.io/kubernetes/pkg/api/unversioned"
+)
+
+// ActiveDeadlineSecondsOverrideAnnotation is the annotation that may be set on a
+// project to override the ActiveDeadlineSeconds applied to run-once pods created
+// in that project. It takes precedence over any global configuration.
+const ActiveDeadlineSecondsOverrideAnnotation = "openshift.io/active-deadline-seconds-override"
+
+// WouldSetActiveDeadlineSecondsAnnotation is set by the plugin on a pod or
+// pod template, in place of mutating ActiveDeadlineSeconds, when
+// EnforcementMode is Warn or Audit. Its value is the ActiveDeadlineSeconds
+// that would have been applied in Enforce mode.
+const WouldSetActiveDeadlineSecondsAnnotation = "runonceduration.openshift.io/would-set"
+
+// RunOnceDurationEnforcementMode determines whether a resolved
+// ActiveDeadlineSeconds decision is actually applied to the pod or pod
+// template, or merely recorded for observation.
+type RunOnceDurationEnforcementMode string
+
+const (
+	// RunOnceDurationEnforcementModeEnforce applies the resolved
+	// ActiveDeadlineSeconds decision. This is the default.
+	RunOnceDurationEnforcementModeEnforce RunOnceDurationEnforcementMode = "Enforce"
+
+	// RunOnceDurationEnforcementModeWarn leaves ActiveDeadlineSeconds
+	// untouched, annotates the would-be decision, and logs an admission
+	// warning.
+	RunOnceDurationEnforcementModeWarn RunOnceDurationEnforcementMode = "Warn"
+
+	// RunOnceDurationEnforcementModeAudit leaves ActiveDeadlineSeconds
+	// untouched and annotates the would-be decision, without warning.
+	RunOnceDurationEnforcementModeAudit RunOnceDurationEnforcementMode = "Audit"
+)
+
+// RunOnceDurationMode determines how a resolved ActiveDeadlineSeconds bound
+// (from a Rule or from ActiveDeadlineSecondsOverride) is applied to a pod
+// that may already have ActiveDeadlineSeconds set.
+type RunOnceDurationMode string
+
+const (
+	// RunOnceDurationModeOverride unconditionally sets ActiveDeadlineSeconds to
+	// the resolved bound, replacing any user-supplied value. This is the
+	// original, and default, behavior.
+	RunOnceDurationModeOverride RunOnceDurationMode = "Override"
+
+	// RunOnceDurationModeMin raises ActiveDeadlineSeconds to the resolved bound
+	// if the pod has no value or a lower one, and otherwise leaves it alone.
+	RunOnceDurationModeMin RunOnceDurationMode = "Min"
+
+	// RunOnceDurationModeMax lowers ActiveDeadlineSeconds to the resolved bound
+	// if the pod has no value or a higher one, and otherwise leaves it alone.
+	RunOnceDurationModeMax RunOnceDurationMode = "Max"
+
+	// RunOnceDurationModeClamp constrains ActiveDeadlineSeconds to the range
+	// [MinActiveDeadlineSeconds, resolved bound], raising or lowering a
+	// user-supplied value as needed.
+	RunOnceDurationModeClamp RunOnceDurationMode = "Clamp"
+)
+
+// RunOnceDurationConfig is the configuration for the RunOnceDuration plugin.
+// It specifies an ActiveDeadlineSeconds bound for a run-once pod, applied
+// according to Mode, that the admission plugin will use unless a more specific override
+// (a per-project annotation, or a matching Rule) takes precedence.
+type RunOnceDurationConfig struct {
+	unversioned.TypeMeta
+
+	// Mode determines how the resolved ActiveDeadlineSeconds bound is applied
+	// to a pod that already has ActiveDeadlineSeconds set. It defaults to
+	// Override.
+	Mode RunOnceDurationMode
+
+	// EnforcementMode determines whether the resolved ActiveDeadlineSeconds
+	// decision is applied, or only recorded for observation. It defaults to
+	// Enforce.
+	EnforcementMode RunOnceDurationEnforcementMode
+
+	// ActiveDeadlineSecondsOverride is the value to override each run-once pod's
+	// ActiveDeadlineSeconds value with. It applies only when no Rule matches the
+	// pod and no per-project annotation override is present. In Max and Clamp
+	// modes, it is used as the ceiling of the allowed range.
+	ActiveDeadlineSecondsOverride *int64
+
+	// MinActiveDeadlineSeconds is the floor of the allowed range when Mode is
+	// Clamp. It is ignored for all other modes.
+	MinActiveDeadlineSeconds *int64
+
+	// Rules is an ordered list of policies for setting ActiveDeadlineSeconds on
+	// run-once pods. Rules are evaluated in order and the first rule whose
+	// selectors match the pod is applied. A matching rule takes precedence over
+	// ActiveDeadlineSecondsOverride, but a per-project
+	// ActiveDeadlineSecondsOverrideAnnotation always takes precedence over Rules.
+	Rules []RunOnceDurationRule
+}
+
+// RunOnceDurationRule describes a policy that applies an ActiveDeadlineSeconds
+// value to run-once pods matching a set of selectors.
+type RunOnceDurationRule struct {
+	// Name identifies the rule in events and logs.
+	Name string
+
+	// NamespaceSelector, if present, restricts the rule to namespaces whose
+	// labels match.
+	NamespaceSelector *unversioned.LabelSelector
+
+	// Selector, if present, restricts the rule to pods whose labels match.
+	Selector *unversioned.LabelSelector
+
+	// ContainerNamePattern, if set, restricts the rule to pods that have at
+	// least one container whose name or image matches the glob pattern.
+	ContainerNamePattern string
+
+	// ActiveDeadlineSeconds is the value applied to pods matched by this rule.
+	ActiveDeadlineSeconds int64
+}
@@ -0,0 +1,18 @@
+package v1
+
+import (
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+func addDefaultingFuncs(scheme *runtime.Scheme) error {
+	return scheme.AddDefaultingFuncs(
+		func(obj *RunOnceDurationConfig) {
+			if len(obj.Mode) == 0 {
+				obj.Mode = RunOnceDurationModeOverride
+			}
+			if len(obj.EnforcementMode) == 0 {
+				obj.EnforcementMode = RunOnceDurationEnforcementModeEnforce
+			}
+		},
+	)
+}
@@ -0,0 +1,21 @@
+package v1
+
+import (
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/runtime"
+)
+
+// SchemeGroupVersion is the v1 group version used to register the
+// runonceduration admission config types.
+var SchemeGroupVersion = unversioned.GroupVersion{Group: "", Version: "v1"}
+
+// SchemeBuilder collects functions that add v1 types to the
+// runonceduration admission config API group.
+var SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes, addDefaultingFuncs)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&RunOnceDurationConfig{},
+	)
+	return nil
+}
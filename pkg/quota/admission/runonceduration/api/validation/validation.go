@@ -0,0 +1,94 @@
+package validation
+
+import (
+	"k8s.io/kubernetes/pkg/api/unversioned"
+	"k8s.io/kubernetes/pkg/util/validation/field"
+
+	"github.com/openshift/origin/pkg/quota/admission/runonceduration/api"
+)
+
+// ValidateRunOnceDurationConfig validates the RunOnceDuration admission plugin
+// configuration.
+func ValidateRunOnceDurationConfig(config *api.RunOnceDurationConfig) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	switch config.Mode {
+	case "", api.RunOnceDurationModeOverride, api.RunOnceDurationModeMin, api.RunOnceDurationModeMax, api.RunOnceDurationModeClamp:
+		// valid
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("mode"), config.Mode,
+			[]string{string(api.RunOnceDurationModeOverride), string(api.RunOnceDurationModeMin), string(api.RunOnceDurationModeMax), string(api.RunOnceDurationModeClamp)}))
+	}
+
+	switch config.EnforcementMode {
+	case "", api.RunOnceDurationEnforcementModeEnforce, api.RunOnceDurationEnforcementModeWarn, api.RunOnceDurationEnforcementModeAudit:
+		// valid
+	default:
+		allErrs = append(allErrs, field.NotSupported(field.NewPath("enforcementMode"), config.EnforcementMode,
+			[]string{string(api.RunOnceDurationEnforcementModeEnforce), string(api.RunOnceDurationEnforcementModeWarn), string(api.RunOnceDurationEnforcementModeAudit)}))
+	}
+
+	if config.ActiveDeadlineSecondsOverride != nil && *config.ActiveDeadlineSecondsOverride <= 0 {
+		allErrs = append(allErrs, field.Invalid(field.NewPath("activeDeadlineSecondsOverride"), *config.ActiveDeadlineSecondsOverride, "must be greater than 0"))
+	}
+
+	if config.MinActiveDeadlineSeconds != nil {
+		if *config.MinActiveDeadlineSeconds <= 0 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("minActiveDeadlineSeconds"), *config.MinActiveDeadlineSeconds, "must be greater than 0"))
+		}
+		if config.Mode == api.RunOnceDurationModeClamp && config.ActiveDeadlineSecondsOverride != nil && *config.MinActiveDeadlineSeconds > *config.ActiveDeadlineSecondsOverride {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("minActiveDeadlineSeconds"), *config.MinActiveDeadlineSeconds, "must not be greater than activeDeadlineSecondsOverride"))
+		}
+	}
+
+	var ruleMin *int64
+	if config.Mode == api.RunOnceDurationModeClamp {
+		ruleMin = config.MinActiveDeadlineSeconds
+	}
+
+	names := map[string]bool{}
+	rulesPath := field.NewPath("rules")
+	for i, rule := range config.Rules {
+		rulePath := rulesPath.Index(i)
+		allErrs = append(allErrs, validateRunOnceDurationRule(rule, rulePath, ruleMin)...)
+		if len(rule.Name) > 0 {
+			if names[rule.Name] {
+				allErrs = append(allErrs, field.Duplicate(rulePath.Child("name"), rule.Name))
+			}
+			names[rule.Name] = true
+		}
+	}
+
+	return allErrs
+}
+
+// validateRunOnceDurationRule validates a single rule. minActiveDeadlineSeconds
+// is the config's MinActiveDeadlineSeconds when Mode is Clamp, nil otherwise;
+// in Clamp mode it is the floor of the range a rule's ActiveDeadlineSeconds is
+// clamped into, so a rule whose bound is below that floor would describe an
+// empty range.
+func validateRunOnceDurationRule(rule api.RunOnceDurationRule, fldPath *field.Path, minActiveDeadlineSeconds *int64) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if len(rule.Name) == 0 {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), ""))
+	}
+	if rule.ActiveDeadlineSeconds <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("activeDeadlineSeconds"), rule.ActiveDeadlineSeconds, "must be greater than 0"))
+	}
+	if minActiveDeadlineSeconds != nil && rule.ActiveDeadlineSeconds < *minActiveDeadlineSeconds {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("activeDeadlineSeconds"), rule.ActiveDeadlineSeconds, "must not be less than minActiveDeadlineSeconds"))
+	}
+	if rule.NamespaceSelector != nil {
+		if _, err := unversioned.LabelSelectorAsSelector(rule.NamespaceSelector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("namespaceSelector"), rule.NamespaceSelector, err.Error()))
+		}
+	}
+	if rule.Selector != nil {
+		if _, err := unversioned.LabelSelectorAsSelector(rule.Selector); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("selector"), rule.Selector, err.Error()))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,46 @@
+package runonceduration
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	admissionTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runonceduration_admission_total",
+			Help: "Number of ActiveDeadlineSeconds decisions made by the RunOnceDuration admission plugin, by source, namespace, and whether the decision was actually enforced (as opposed to only recorded in Warn/Audit mode).",
+		},
+		[]string{"source", "namespace", "enforced"},
+	)
+
+	appliedActiveDeadlineSeconds = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "runonceduration_admission_active_deadline_seconds",
+			Help:    "Distribution of ActiveDeadlineSeconds values decided by the RunOnceDuration admission plugin, whether or not they were actually enforced.",
+			Buckets: prometheus.ExponentialBuckets(30, 2, 12),
+		},
+	)
+
+	registerMetricsOnce sync.Once
+)
+
+// registerMetrics registers the plugin's metrics with the default
+// Prometheus registry. It is safe to call more than once.
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		prometheus.MustRegister(admissionTotal)
+		prometheus.MustRegister(appliedActiveDeadlineSeconds)
+	})
+}
+
+// observeAdmission records that the plugin decided to set a pod's
+// ActiveDeadlineSeconds to deadline because of source, in namespace.
+// enforced distinguishes a decision that was actually applied to the pod
+// from one that was only recorded, in Warn/Audit mode.
+func observeAdmission(source, namespace string, deadline int64, enforced bool) {
+	admissionTotal.WithLabelValues(source, namespace, strconv.FormatBool(enforced)).Inc()
+	appliedActiveDeadlineSeconds.Observe(float64(deadline))
+}